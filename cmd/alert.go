@@ -0,0 +1,88 @@
+package main
+
+import "time"
+
+// alertState tracks the alerting condition across ticks so that
+// compareTemperatures can de-duplicate repeat alerts, require sustained
+// over-threshold samples, and detect recovery via hysteresis. It also
+// retains recent readings and the process start time for use in
+// notification templates
+type alertState struct {
+	consecutiveOver int
+	alerting        bool
+	lastAlertAt     time.Time
+	startTime       time.Time
+	samples         []float64
+}
+
+// newAlertState returns a freshly armed alertState
+func newAlertState() *alertState {
+	return &alertState{startTime: time.Now()}
+}
+
+// recordSample appends currentTemp to the rolling window of recent readings,
+// retaining at most maxSamples entries
+func (s *alertState) recordSample(currentTemp float64) {
+
+	s.samples = append(s.samples, currentTemp)
+
+	if len(s.samples) > maxSamples {
+		s.samples = s.samples[len(s.samples)-maxSamples:]
+	}
+}
+
+// uptime returns how long the monitor has been running
+func (s *alertState) uptime() time.Duration {
+	return time.Since(s.startTime)
+}
+
+// evaluate advances the alert state machine for the given reading against
+// threshold (with the shared hysteresis/sustained/cooldown settings) and
+// reports the current event and whether it should be sent. eventType is one
+// of "alert", "recovery", or "" when there is no event this tick; while an
+// alert is still within the cooldown window it is "alert" with send false
+func (s *alertState) evaluate(threshold, hysteresis float64, sustained int, cooldown time.Duration, currentTemp float64) (eventType string, send bool) {
+
+	if currentTemp > threshold {
+		if s.alerting {
+			// Already alerting: the condition only actually clears via the
+			// hysteresis recovery branch below, so a reading that merely
+			// dips into the threshold/hysteresis dead zone must not reset
+			// the sustained counter and force a repeat alert to reaccumulate
+			s.consecutiveOver = sustained
+		} else {
+			s.consecutiveOver++
+		}
+
+		if s.consecutiveOver < sustained {
+			return "", false
+		}
+
+		if !s.alerting {
+			s.alerting = true
+			s.lastAlertAt = time.Time{} // force an immediate alert the first time the condition trips
+		}
+
+		if time.Since(s.lastAlertAt) < cooldown {
+			return "alert", false // still alerting, but within the cooldown window
+		}
+
+		s.lastAlertAt = time.Now()
+
+		return "alert", true
+	}
+
+	if !s.alerting {
+		s.consecutiveOver = 0
+	}
+
+	if s.alerting && currentTemp <= threshold-hysteresis {
+		s.consecutiveOver = 0
+		s.alerting = false
+		s.lastAlertAt = time.Now()
+
+		return "recovery", true
+	}
+
+	return "", false
+}
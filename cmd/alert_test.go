@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertStateEvaluateSustained(t *testing.T) {
+
+	s := newAlertState()
+
+	// Below the sustained count, no alert should fire yet
+	if event, send := s.evaluate(60, 2, 3, 0, 65); send || event != "" {
+		t.Fatalf("sample 1: got (%q, %v), want (\"\", false)", event, send)
+	}
+
+	if event, send := s.evaluate(60, 2, 3, 0, 65); send || event != "" {
+		t.Fatalf("sample 2: got (%q, %v), want (\"\", false)", event, send)
+	}
+
+	// Third consecutive over-threshold sample reaches the sustained count
+	event, send := s.evaluate(60, 2, 3, 0, 65)
+	if !send || event != "alert" {
+		t.Fatalf("sample 3: got (%q, %v), want (\"alert\", true)", event, send)
+	}
+}
+
+func TestAlertStateEvaluateCooldown(t *testing.T) {
+
+	s := newAlertState()
+
+	event, send := s.evaluate(60, 2, 1, time.Hour, 65)
+	if !send || event != "alert" {
+		t.Fatalf("initial trip: got (%q, %v), want (\"alert\", true)", event, send)
+	}
+
+	// Still over threshold, but within the cooldown window: the alert event
+	// type is reported but send is false so no repeat notification goes out
+	event, send = s.evaluate(60, 2, 1, time.Hour, 66)
+	if send || event != "alert" {
+		t.Fatalf("within cooldown: got (%q, %v), want (\"alert\", false)", event, send)
+	}
+}
+
+func TestAlertStateEvaluateHysteresisRecovery(t *testing.T) {
+
+	s := newAlertState()
+
+	if _, send := s.evaluate(60, 2, 1, 0, 65); !send {
+		t.Fatal("expected initial trip to alert")
+	}
+
+	// Dipping back below threshold but still inside the hysteresis dead zone
+	// must not clear the alert
+	if event, send := s.evaluate(60, 2, 1, 0, 59); send || event != "" {
+		t.Fatalf("dead zone: got (%q, %v), want (\"\", false)", event, send)
+	}
+
+	if !s.alerting {
+		t.Fatal("alert state should still be armed after a dead-zone dip")
+	}
+
+	// Dropping to (or below) threshold-hysteresis clears the alert
+	event, send := s.evaluate(60, 2, 1, 0, 57)
+	if !send || event != "recovery" {
+		t.Fatalf("recovery: got (%q, %v), want (\"recovery\", true)", event, send)
+	}
+
+	if s.alerting {
+		t.Fatal("alert state should be cleared after recovery")
+	}
+}
+
+// TestAlertStateEvaluateDeadZoneDoesNotDelayRepeatAlert guards against the
+// regression fixed in 6248a1e: a brief hysteresis-zone dip while already
+// alerting must not force the sustained counter to reaccumulate before the
+// next repeat alert can fire.
+func TestAlertStateEvaluateDeadZoneDoesNotDelayRepeatAlert(t *testing.T) {
+
+	s := newAlertState()
+
+	if _, send := s.evaluate(60, 2, 3, 0, 65); send {
+		t.Fatal("sample 1 should not alert yet")
+	}
+
+	if _, send := s.evaluate(60, 2, 3, 0, 65); send {
+		t.Fatal("sample 2 should not alert yet")
+	}
+
+	if event, send := s.evaluate(60, 2, 3, 0, 65); !send || event != "alert" {
+		t.Fatalf("sample 3: got (%q, %v), want (\"alert\", true)", event, send)
+	}
+
+	// A brief dip into the dead zone (below threshold, above threshold-hysteresis)
+	if event, send := s.evaluate(60, 2, 3, 0, 59); send || event != "" {
+		t.Fatalf("dead-zone dip: got (%q, %v), want (\"\", false)", event, send)
+	}
+
+	// Back over threshold on the very next sample: since the alert condition
+	// never actually cleared, this should immediately qualify as sustained
+	// again rather than needing 3 more consecutive samples
+	if event, send := s.evaluate(60, 2, 3, 0, 65); !send || event != "alert" {
+		t.Fatalf("repeat alert after dip: got (%q, %v), want (\"alert\", true)", event, send)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// newStructuredLogger returns a slog.Logger that writes JSON records to
+// stdout, used when '-log-format json' is set. The default "time" key is
+// renamed to "ts" to match the monitor's field naming
+func newStructuredLogger() *slog.Logger {
+
+	opts := &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				a.Key = "ts"
+			}
+			return a
+		},
+	}
+
+	return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+}
+
+// logInfo, logWarn, and logError route every log line in the application
+// (startup/shutdown banners, configuration, notifier attempts, errors) through
+// the structured logger when '-log-format json' is set, so that the log output
+// is either all plain text (via the log package, to stderr) or all JSON (to
+// stdout), never a mix of the two
+func logInfo(structured *slog.Logger, msg string)  { logLine(structured, slog.LevelInfo, msg) }
+func logWarn(structured *slog.Logger, msg string)  { logLine(structured, slog.LevelWarn, msg) }
+func logError(structured *slog.Logger, msg string) { logLine(structured, slog.LevelError, msg) }
+
+// logLine writes msg via the stock log package, or as a structured JSON
+// record when structured is non-nil
+func logLine(structured *slog.Logger, level slog.Level, msg string) {
+
+	if structured == nil {
+		log.Print(msg)
+		return
+	}
+
+	structured.Log(context.Background(), level, msg)
+}
+
+// logTempReading records a single temperature reading, either as a plain
+// log.Printf line or as a structured JSON record when structured is non-nil
+func logTempReading(cfg config, structured *slog.Logger, sn sensor, currentTemp float64, event string) {
+
+	if structured == nil {
+		if event == "" {
+			log.Printf("Current %q temperature: %.2f°C", sn.Name, currentTemp)
+		} else {
+			log.Printf("%s: %q temperature %.2f°C (threshold %.2f°C)", strings.ToUpper(event), sn.Name, currentTemp, sn.Threshold)
+		}
+
+		return
+	}
+
+	level := slog.LevelInfo
+	if event == "alert" {
+		level = slog.LevelWarn
+	}
+
+	if event == "" {
+		event = "reading"
+	}
+
+	structured.Log(context.Background(), level, "temperature reading",
+		"hostname", cfg.Hostname,
+		"sensor", sn.Name,
+		"temp_c", currentTemp,
+		"threshold_c", sn.Threshold,
+		"event", event,
+	)
+}
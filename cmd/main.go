@@ -1,17 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/exec"
 	"os/signal"
-	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -23,15 +21,16 @@ const (
 	appVersion       = "0.7.0"
 	noEmailRecipient = "<none>"
 
-	cpuTempFilePath = "/sys/class/thermal/thermal_zone0/temp"
-	mailCommand     = "/usr/bin/mail"
+	cpuTempFilePath    = "/sys/class/thermal/thermal_zone0/temp"
+	defaultMailCommand = "/usr/bin/mail"
+	defaultSendmail    = "/usr/sbin/sendmail"
+	defaultNotifier    = "mail"
 )
 
 // Application errors
 var (
-	errIsDirectory                = errors.New("'mail' command points to a directory")
-	errNotExecutable              = errors.New("'mail' command is not executable")
-	errTestEmailRequiresRecipient = errors.New("'-test-email' flag requires '-recipient' flag to be set")
+	errIsDirectory   = errors.New("'mail' command points to a directory")
+	errNotExecutable = errors.New("'mail' command is not executable")
 )
 
 // Application configuration flags
@@ -41,57 +40,155 @@ type config struct {
 	CheckInterval  time.Duration
 	TestEmailFlag  bool
 	Hostname       string
+
+	Notifiers       []string
+	MailCommand     string
+	SendmailCommand string
+	WebhookURL      string
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUser        string
+	SMTPPass        string
+
+	AlertCooldown time.Duration
+	Hysteresis    float64
+	Sustained     int
+
+	SubjectTemplatePath string
+	BodyTemplatePath    string
+
+	SensorConfigPath string
+
+	MetricsAddr string
+	LogFormat   string
+}
+
+// monitor bundles the dependencies needed to run a sensor check: where to
+// send notifications, how to render them, and how to log readings
+type monitor struct {
+	Notifier  Notifier
+	Templates notificationTemplates
+	Logger    *slog.Logger
 }
 
 func main() {
 
-	hello()
 	cfg := parseFlags()
 	cfg.Hostname = getHostname()
 
-	if err := validateMailCommand(mailCommand); err != nil {
-		log.Printf("%v", err)
-		goodbye()
+	var structuredLogger *slog.Logger
+	if cfg.LogFormat == "json" {
+		structuredLogger = newStructuredLogger()
+	}
+
+	hello(structuredLogger)
+
+	if containsNotifier(cfg.Notifiers, "mail") {
+		if err := validateMailCommand(cfg.MailCommand); err != nil {
+			logError(structuredLogger, fmt.Sprintf("%v", err))
+			goodbye(structuredLogger)
+		}
 	}
 
-	log.Println(appPrefix, "Configuration")
-	showConfiguration(&cfg)
+	notifier, err := buildNotifiers(cfg, structuredLogger)
+	if err != nil {
+		logError(structuredLogger, fmt.Sprintf("Error configuring notifiers: %v", err))
+		goodbye(structuredLogger)
+	}
+
+	tmpl, err := loadTemplates(cfg)
+	if err != nil {
+		logError(structuredLogger, fmt.Sprintf("Error loading notification templates: %v", err))
+		goodbye(structuredLogger)
+	}
+
+	sensors, err := buildSensors(cfg)
+	if err != nil {
+		logError(structuredLogger, fmt.Sprintf("Error configuring sensors: %v", err))
+		goodbye(structuredLogger)
+	}
+
+	if cfg.MetricsAddr != "" {
+		startMetricsServer(cfg.MetricsAddr, structuredLogger)
+	}
+
+	mon := monitor{Notifier: notifier, Templates: tmpl, Logger: structuredLogger}
+
+	logInfo(structuredLogger, fmt.Sprintf("%s Configuration", appPrefix))
+	showConfiguration(&cfg, sensors, structuredLogger)
 
 	// Check if -test-email flag is set
 	if cfg.TestEmailFlag {
-		if err := sendTestEmail(cfg); err != nil {
-			log.Printf("Error sending test email: %v", err)
+		if err := sendTestEmail(cfg, sensors[0], mon); err != nil {
+			logError(structuredLogger, fmt.Sprintf("Error sending test email: %v", err))
 		}
-		goodbye()
+		goodbye(structuredLogger)
 	}
 
-	log.Println(appPrefix, "Monitoring")
-	compareTemperatures(cfg) // Initial check before starting loop
-	tempCheckLoop(cfg)
+	logInfo(structuredLogger, fmt.Sprintf("%s Monitoring", appPrefix))
+	runSensorLoops(cfg, sensors, mon)
 }
 
-// tempCheckLoop runs the main loop to check temperature and send alerts
-func tempCheckLoop(cfg config) {
+// containsNotifier reports whether name is present in notifiers
+func containsNotifier(notifiers []string, name string) bool {
 
-	ticker := time.NewTicker(cfg.CheckInterval)
-	defer ticker.Stop()
+	for _, n := range notifiers {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runSensorLoops runs one independent check loop per sensor, each with its
+// own ticker and alert state, until a shutdown signal is received
+func runSensorLoops(cfg config, sensors []sensor, mon monitor) {
+
+	ctx, cancel := context.WithCancel(context.Background())
 
 	// Set up signal handler to monitor interrupts
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	var wg sync.WaitGroup
+
+	for _, sn := range sensors {
+		wg.Add(1)
+
+		go func(sn sensor) {
+			defer wg.Done()
+			sensorLoop(ctx, cfg, sn, mon)
+		}(sn)
+	}
+
+	sig := <-sigChan
+	fmt.Print("\r") // Clear the ^C character from the terminal line
+	logInfo(mon.Logger, fmt.Sprintf("Received signal %s: shutting down", sig))
+
+	cancel()
+	wg.Wait()
+	goodbye(mon.Logger)
+}
+
+// sensorLoop runs the check loop for a single sensor until ctx is canceled
+func sensorLoop(ctx context.Context, cfg config, sn sensor, mon monitor) {
+
+	state := newAlertState()
+	compareTemperatures(ctx, cfg, sn, state, mon) // Initial check before starting loop
+
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
-			compareTemperatures(cfg)
+			compareTemperatures(ctx, cfg, sn, state, mon)
 
-		case sig := <-sigChan:
-			fmt.Print("\r") // Clear the ^C character from the terminal line
-			log.Printf("Received signal %s: shutting down", sig)
-			goodbye()
+		case <-ctx.Done():
+			return
 		}
 	}
-
 }
 
 // validateMailCommand checks if the mail command is valid
@@ -116,21 +213,32 @@ func validateMailCommand(mailCommand string) error {
 }
 
 // showConfiguration displays the current configuration
-func showConfiguration(cfg *config) {
+func showConfiguration(cfg *config, sensors []sensor, structured *slog.Logger) {
+
+	logInfo(structured, "|")
+	logInfo(structured, fmt.Sprintf("| Application version: %s", appVersion))
+	logInfo(structured, fmt.Sprintf("| Check interval ('-interval'): %s", cfg.CheckInterval))
+	logInfo(structured, fmt.Sprintf("| Alert cooldown ('-alert-cooldown'): %s", cfg.AlertCooldown))
+	logInfo(structured, fmt.Sprintf("| Hysteresis ('-hysteresis'): %.2f°C", cfg.Hysteresis))
+	logInfo(structured, fmt.Sprintf("| Sustained samples ('-sustained'): %d", cfg.Sustained))
+	logInfo(structured, fmt.Sprintf("| Log format ('-log-format'): %s", cfg.LogFormat))
+
+	if cfg.MetricsAddr != "" {
+		logInfo(structured, fmt.Sprintf("| Metrics address ('-metrics-addr'): %s", cfg.MetricsAddr))
+	}
 
-	log.Printf("|\n")
-	log.Printf("| Application version: %s\n", appVersion)
-	log.Printf("| Temperature threshold ('-threshold'): %.2f°C\n", cfg.TempThreshold)
-	log.Printf("| Check interval ('-interval'): %s\n", cfg.CheckInterval)
+	for _, sn := range sensors {
+		logInfo(structured, fmt.Sprintf("| Sensor %q threshold: %.2f°C", sn.Name, sn.Threshold))
+	}
 
 	if cfg.EmailRecipient == "" {
 		cfg.EmailRecipient = noEmailRecipient
 	}
 
-	log.Printf("| Email recipient ('-recipient'): %s\n", cfg.EmailRecipient)
-	log.Printf("| Mail command: %s\n", mailCommand)
-	log.Printf("| Device hostname: %s\n", cfg.Hostname)
-	log.Printf("|\n")
+	logInfo(structured, fmt.Sprintf("| Email recipient ('-recipient'): %s", cfg.EmailRecipient))
+	logInfo(structured, fmt.Sprintf("| Notifiers ('-notifier'): %s", strings.Join(cfg.Notifiers, ",")))
+	logInfo(structured, fmt.Sprintf("| Device hostname: %s", cfg.Hostname))
+	logInfo(structured, "|")
 
 }
 
@@ -149,143 +257,143 @@ func getHostname() string {
 func parseFlags() config {
 
 	cfg := config{}
+	var notifiers string
+
 	flag.StringVar(&cfg.EmailRecipient, "recipient", "", "Recipient email address for alert notifications")
 	flag.Float64Var(&cfg.TempThreshold, "threshold", 60.0, "CPU temperature (Celsius) threshold")
 	flag.DurationVar(&cfg.CheckInterval, "interval", 5*time.Minute, "Interval for checking CPU temperature")
 	flag.BoolVar(&cfg.TestEmailFlag, "test-email", false, "Send a test email and exit")
+	flag.StringVar(&notifiers, "notifier", defaultNotifier, "Comma-separated notifiers to use (mail, sendmail, smtp, webhook)")
+	flag.StringVar(&cfg.MailCommand, "mail-command", defaultMailCommand, "Path to the 'mail' binary used by the 'mail' notifier")
+	flag.StringVar(&cfg.SendmailCommand, "sendmail", defaultSendmail, "Path to a sendmail-compatible binary used by the 'sendmail' notifier")
+	flag.StringVar(&cfg.WebhookURL, "webhook-url", "", "Webhook URL used by the 'webhook' notifier")
+	flag.StringVar(&cfg.SMTPHost, "smtp-host", os.Getenv("SMTP_HOST"), "SMTP server host used by the 'smtp' notifier")
+	flag.StringVar(&cfg.SMTPPort, "smtp-port", "587", "SMTP server port used by the 'smtp' notifier")
+	flag.StringVar(&cfg.SMTPUser, "smtp-user", os.Getenv("SMTP_USER"), "SMTP username used by the 'smtp' notifier")
+	flag.StringVar(&cfg.SMTPPass, "smtp-pass", os.Getenv("SMTP_PASS"), "SMTP password used by the 'smtp' notifier")
+	flag.DurationVar(&cfg.AlertCooldown, "alert-cooldown", 30*time.Minute, "Minimum interval between repeat alerts for the same condition")
+	flag.Float64Var(&cfg.Hysteresis, "hysteresis", 2.0, "Degrees (Celsius) below threshold required before the 'OK' state is re-armed")
+	flag.IntVar(&cfg.Sustained, "sustained", 1, "Number of consecutive over-threshold samples required before alerting")
+	flag.StringVar(&cfg.SubjectTemplatePath, "subject-template", "", "Path to a text/template file for the notification subject (built-in default if unset)")
+	flag.StringVar(&cfg.BodyTemplatePath, "body-template", "", "Path to a text/template file for the notification body (built-in default if unset)")
+	flag.StringVar(&cfg.SensorConfigPath, "sensors-config", "", "Path to a YAML config file declaring multiple sensors to monitor (single CPU sensor via '-threshold' if unset)")
+	flag.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on, e.g. ':9100' (disabled if unset)")
+	flag.StringVar(&cfg.LogFormat, "log-format", "text", "Log format for all log output: 'text' or 'json'")
 	flag.Parse()
 
+	cfg.Notifiers = strings.Split(notifiers, ",")
+
 	return cfg
 }
 
-// sendTestEmail sends a test email using the configured mail command
-func sendTestEmail(cfg config) error {
+// sendTestEmail sends a test notification for sn using the configured
+// notifiers. buildNotifiers already rejects email-based notifiers (mail,
+// sendmail, smtp) when '-recipient' is unset, so no recipient is required
+// here for an email-less notifier such as webhook
+func sendTestEmail(cfg config, sn sensor, mon monitor) error {
 
-	// Check if recipient is set
-	if cfg.EmailRecipient == noEmailRecipient {
-		return errTestEmailRequiresRecipient
+	readCtx, readCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	currentTemp, err := sn.Source.ReadTemp(readCtx)
+	readCancel()
+	if err != nil {
+		return err
 	}
 
-	// Get current CPU temperature
-	currentTemp, err := getCPUTemperature()
+	subject, body, err := mon.Templates.render(templateContext{
+		Hostname:    cfg.Hostname,
+		Sensor:      sn.Name,
+		CurrentTemp: currentTemp,
+		Threshold:   sn.Threshold,
+		Timestamp:   time.Now().Format(time.RFC1123),
+		EventType:   "test",
+		Samples:     []float64{currentTemp},
+	})
 	if err != nil {
 		return err
 	}
 
-	// Create subject and body
-	subject := fmt.Sprintf("%s: Test Alert (%s)", appName, cfg.Hostname)
-	body := fmt.Sprintf("Warning: this is a test email\nHostname: %s\nCurrent CPU temperature: %.2f°C\nTimestamp: %s",
-		cfg.Hostname, currentTemp, time.Now().Format(time.RFC1123))
+	// No overall deadline here: RetryingNotifier bounds each individual
+	// attempt itself, so the 2s and 8s backoff waits between its 3 attempts
+	// are not cut short by a caller deadline
+	err = mon.Notifier.Notify(context.Background(), subject, body)
+	recordSendResult(err)
+	alertsTotal.WithLabelValues("test").Inc()
 
-	if err := sendEmail(cfg, subject, body); err != nil {
+	if err != nil {
 		return fmt.Errorf("%w", err)
 	}
 
 	return nil
 }
 
-// sendEmail sends an email using the configured mail command
-func sendEmail(cfg config, subject, body string) error {
+// compareTemperatures checks sn's current temperature against its threshold
+// and advances the alert state machine, sending an alert, a repeat alert, or
+// an "all clear" recovery notification as the state machine dictates
+func compareTemperatures(ctx context.Context, cfg config, sn sensor, state *alertState, mon monitor) {
 
-	if cfg.EmailRecipient == noEmailRecipient {
-		log.Println("Email recipient not set: no email will be sent.")
-		return nil // Not an error, just won't send
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second) // 15s timeout for mail command
-	defer cancel()
-
-	// Sanitize subject and recipient before passing to mail command (per GOSEC:G204)
-	sanitizedSubject := strings.ReplaceAll(subject, ";", "")
-	sanitizedRecipient := strings.ReplaceAll(cfg.EmailRecipient, ";", "")
-	cmd := exec.CommandContext(ctx, mailCommand, "-s", sanitizedSubject, sanitizedRecipient)
-
-	cmd.Stdin = strings.NewReader(body)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	log.Printf("Attempting to send email to %s", cfg.EmailRecipient)
+	checkStart := time.Now()
+	defer func() { checkDurationSeconds.Observe(time.Since(checkStart).Seconds()) }()
 
-	if err := cmd.Run(); err != nil {
-		errMsg := fmt.Sprintf("failed to send email: %v", err)
+	readCtx, readCancel := context.WithTimeout(ctx, 5*time.Second)
+	currentTemp, err := sn.Source.ReadTemp(readCtx)
+	readCancel()
 
-		if stderr.Len() > 0 {
-			errMsg += fmt.Sprintf(". Stderr: %s", stderr.String())
-		}
-
-		// Check if context deadline exceeded while sending email
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			log.Printf("%s (timed out)", errMsg)
-			return fmt.Errorf("%s (timed out): %w", errMsg, context.DeadlineExceeded)
-		}
-
-		log.Print(errMsg)
+	if err != nil {
+		logError(mon.Logger, fmt.Sprintf("Error reading %q sensor temperature: %v", sn.Name, err))
 
-		return fmt.Errorf("%s: %w", errMsg, err)
+		return
 	}
 
-	log.Printf("Email sent successfully to %s", cfg.EmailRecipient)
-
-	return nil
-}
-
-// getCPUTemperature returns the current CPU temperature in Celsius
-func getCPUTemperature() (float64, error) {
+	tempCelsius.WithLabelValues(sn.Name).Set(currentTemp)
+	tempThresholdCelsius.WithLabelValues(sn.Name).Set(sn.Threshold)
 
-	data, err := os.ReadFile(cpuTempFilePath)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read temperature file %s: %w", cpuTempFilePath, err)
-	}
+	logTempReading(cfg, mon.Logger, sn, currentTemp, "")
+	state.recordSample(currentTemp)
 
-	tempStr := strings.TrimSpace(string(data))
-	tempVal, err := strconv.ParseFloat(tempStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse temperature value '%s': %w", tempStr, err)
+	eventType, send := state.evaluate(sn.Threshold, cfg.Hysteresis, cfg.Sustained, cfg.AlertCooldown, currentTemp)
+	if !send {
+		return
 	}
 
-	return tempVal / 1000.0, nil
-}
-
-// compareTemperatures checks the current temperature against the threshold and sends alerts if necessary
-func compareTemperatures(cfg config) {
-
-	currentTemp, err := getCPUTemperature()
+	logTempReading(cfg, mon.Logger, sn, currentTemp, eventType)
+
+	subject, body, err := mon.Templates.render(templateContext{
+		Hostname:    cfg.Hostname,
+		Sensor:      sn.Name,
+		CurrentTemp: currentTemp,
+		Threshold:   sn.Threshold,
+		Timestamp:   time.Now().Format(time.RFC1123),
+		Uptime:      state.uptime(),
+		EventType:   eventType,
+		Samples:     state.samples,
+	})
 	if err != nil {
-		log.Printf("Error reading CPU temperature: %v", err)
-
+		logError(mon.Logger, fmt.Sprintf("Error rendering %s notification: %v", eventType, err))
 		return
 	}
 
-	log.Printf("Current CPU temperature: %.2f°C", currentTemp)
-
-	if currentTemp > cfg.TempThreshold {
-		log.Printf("ALERT: Temperature %.2f°C exceeds threshold of %.2f°C", currentTemp, cfg.TempThreshold)
-
-		if cfg.EmailRecipient == noEmailRecipient {
-			log.Println("No recipient configured: no email notification sent")
-			return
-		}
+	logInfo(mon.Logger, fmt.Sprintf("Sending %s notification for %s", eventType, sn.Name))
+	alertsTotal.WithLabelValues(eventType).Inc()
 
-		log.Println("Sending email notification")
-		subject := fmt.Sprintf("%s: CPU Temp Alert (%s): %.2f°C", appName, cfg.Hostname, currentTemp)
-		body := fmt.Sprintf("Warning: CPU temperature on %s has exceeded threshold\n"+
-			"Threshold temp: %.2f°C\nCurrent temp: %.2f°C\nTimestamp: %s",
-			cfg.Hostname, cfg.TempThreshold, currentTemp, time.Now().Format(time.RFC1123))
-
-		if err := sendEmail(cfg, subject, body); err != nil {
-			log.Printf("Error sending alert email: %v", err)
-		}
+	// ctx here is the long-lived sensor loop context (canceled only on
+	// shutdown), not a short-lived deadline: RetryingNotifier bounds each
+	// individual attempt itself, so the 2s and 8s backoff waits between its
+	// 3 attempts are not cut short
+	err = mon.Notifier.Notify(ctx, subject, body)
+	recordSendResult(err)
 
+	if err != nil {
+		logError(mon.Logger, fmt.Sprintf("Error sending %s notification: %v", eventType, err))
 	}
 }
 
 // hello outputs a welcome message
-func hello() {
-	log.Println(appPrefix, "Starting", appName, appVersion)
+func hello(structured *slog.Logger) {
+	logInfo(structured, fmt.Sprintf("%s Starting %s %s", appPrefix, appName, appVersion))
 }
 
 // goodbye outputs a goodbye message and exits the program
-func goodbye() {
-	log.Println(appPrefix, "Exiting", appName, appVersion)
+func goodbye(structured *slog.Logger) {
+	logInfo(structured, fmt.Sprintf("%s Exiting %s %s", appPrefix, appName, appVersion))
 	os.Exit(0)
 }
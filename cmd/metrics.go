@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics exposed on -metrics-addr
+var (
+	tempCelsius = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "raspi_temp_celsius",
+		Help: "Current sensor temperature in Celsius",
+	}, []string{"sensor"})
+
+	tempThresholdCelsius = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "raspi_temp_threshold_celsius",
+		Help: "Configured alert threshold in Celsius",
+	}, []string{"sensor"})
+
+	alertsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "raspi_temp_alerts_total",
+		Help: "Total number of temperature notifications sent, by event type",
+	}, []string{"event"})
+
+	emailSendTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "raspi_email_send_total",
+		Help: "Total number of notification send attempts, by result",
+	}, []string{"result"})
+
+	checkDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "raspi_temp_check_duration_seconds",
+		Help: "Duration of a temperature check (read, evaluate, and notify) in seconds",
+	})
+)
+
+// startMetricsServer starts the Prometheus metrics HTTP server on addr in
+// the background. Failures are logged but do not stop the monitor
+func startMetricsServer(addr string, structured *slog.Logger) {
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		logInfo(structured, fmt.Sprintf("Starting metrics server on %s", addr))
+
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logError(structured, fmt.Sprintf("Metrics server error: %v", err))
+		}
+	}()
+}
+
+// recordSendResult classifies a notifier error and increments
+// raspi_email_send_total with the matching result label
+func recordSendResult(err error) {
+
+	switch {
+	case err == nil:
+		emailSendTotal.WithLabelValues("success").Inc()
+
+	case errors.Is(err, context.DeadlineExceeded):
+		emailSendTotal.WithLabelValues("timeout").Inc()
+
+	default:
+		emailSendTotal.WithLabelValues("failure").Inc()
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Application errors (notifiers)
+var (
+	errUnknownNotifier   = errors.New("unknown notifier")
+	errNoNotifiers       = errors.New("no notifiers configured")
+	errRecipientRequired = errors.New("'-recipient' flag must be set to use this notifier")
+)
+
+// Retry configuration for temporary notifier failures. RetryingNotifier makes
+// at most len(retryBackoff) attempts, waiting retryBackoff[i] after failed
+// attempt i; the last entry is never waited on, since no attempt follows it.
+// defaultAttemptTimeout bounds a single attempt; a deadline on the context
+// passed to Notify still bounds every attempt and every wait
+var retryBackoff = []time.Duration{2 * time.Second, 8 * time.Second, 30 * time.Second}
+
+const defaultAttemptTimeout = 15 * time.Second
+
+// SendError classifies a notifier failure as temporary (worth retrying) or
+// permanent, akin to go-mail's SendError
+type SendError struct {
+	Err       error
+	Temporary bool
+}
+
+// Error implements the error interface for SendError
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows errors.Is/errors.As to see through to the wrapped error
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// IsTemp reports whether the failure is likely transient and safe to retry
+func (e *SendError) IsTemp() bool {
+	return e.Temporary
+}
+
+// temporaryError is implemented by errors that know whether they're transient
+type temporaryError interface {
+	IsTemp() bool
+}
+
+// isTemporary reports whether err identifies itself as a temporary failure
+func isTemporary(err error) bool {
+
+	var temp temporaryError
+	if errors.As(err, &temp) {
+		return temp.IsTemp()
+	}
+
+	return false
+}
+
+// RetryingNotifier wraps a Notifier, retrying temporary failures with
+// exponential backoff before giving up. Each attempt gets its own
+// AttemptTimeout (defaultAttemptTimeout if unset) derived from the caller's
+// context, so a deadline on the caller's context still bounds the entire
+// retry sequence
+type RetryingNotifier struct {
+	Notifier       Notifier
+	AttemptTimeout time.Duration
+	Logger         *slog.Logger
+}
+
+// Notify implements Notifier for RetryingNotifier
+func (r *RetryingNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	timeout := r.AttemptTimeout
+	if timeout <= 0 {
+		timeout = defaultAttemptTimeout
+	}
+
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = r.Notifier.Notify(attemptCtx, subject, body)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+
+		// len(retryBackoff) total attempts: after the last attempt, give up
+		// without waiting on the final backoff delay
+		if !isTemporary(err) || attempt >= len(retryBackoff)-1 {
+			return err
+		}
+
+		delay := retryBackoff[attempt]
+		logWarn(r.Logger, fmt.Sprintf("Notifier failed with a temporary error, retrying in %s: %v", delay, err))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Notifier sends a notification with the given subject and body
+type Notifier interface {
+	Notify(ctx context.Context, subject, body string) error
+}
+
+// MultiNotifier fans a single notification out to multiple Notifiers
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+// Notify sends the notification to every configured notifier, joining any
+// errors encountered so callers see every failure, not just the first
+func (m *MultiNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	var errs []error
+
+	for _, n := range m.notifiers {
+		if err := n.Notify(ctx, subject, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MailNotifier sends email via the system 'mail' command
+type MailNotifier struct {
+	Command   string
+	Recipient string
+	Logger    *slog.Logger
+}
+
+// Notify implements Notifier for MailNotifier
+func (n *MailNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	// Sanitize subject and recipient before passing to mail command (per GOSEC:G204)
+	sanitizedSubject := strings.ReplaceAll(subject, ";", "")
+	sanitizedRecipient := strings.ReplaceAll(n.Recipient, ";", "")
+	cmd := exec.CommandContext(ctx, n.Command, "-s", sanitizedSubject, sanitizedRecipient)
+
+	cmd.Stdin = strings.NewReader(body)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	logInfo(n.Logger, fmt.Sprintf("Attempting to send email to %s via 'mail'", n.Recipient))
+
+	if err := cmd.Run(); err != nil {
+		return classifyExecError(ctx, "mail", err, stderr.String(), n.Logger)
+	}
+
+	logInfo(n.Logger, fmt.Sprintf("Email sent successfully to %s via 'mail'", n.Recipient))
+
+	return nil
+}
+
+// SendmailNotifier sends email by piping an RFC 5322 message to a
+// sendmail-compatible binary's stdin
+type SendmailNotifier struct {
+	Command   string
+	Recipient string
+	From      string
+	Logger    *slog.Logger
+}
+
+// Notify implements Notifier for SendmailNotifier
+func (n *SendmailNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	cmd := exec.CommandContext(ctx, n.Command, "-t")
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", n.From, n.Recipient, subject, body)
+	cmd.Stdin = strings.NewReader(message)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	logInfo(n.Logger, fmt.Sprintf("Attempting to send email to %s via '%s'", n.Recipient, n.Command))
+
+	if err := cmd.Run(); err != nil {
+		return classifyExecError(ctx, "sendmail", err, stderr.String(), n.Logger)
+	}
+
+	logInfo(n.Logger, fmt.Sprintf("Email sent successfully to %s via '%s'", n.Recipient, n.Command))
+
+	return nil
+}
+
+// classifyExecError wraps a failed exec.Cmd run as a SendError, marking it
+// temporary when the context deadline was exceeded or stderr contains
+// "temporary failure" (case-insensitive)
+func classifyExecError(ctx context.Context, via string, err error, stderrOutput string, structured *slog.Logger) error {
+
+	errMsg := fmt.Sprintf("%s: failed to send email: %v", via, err)
+	if stderrOutput != "" {
+		errMsg += fmt.Sprintf(". Stderr: %s", stderrOutput)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logWarn(structured, fmt.Sprintf("%s (timed out)", errMsg))
+		return &SendError{Err: fmt.Errorf("%s (timed out): %w", errMsg, context.DeadlineExceeded), Temporary: true}
+	}
+
+	logError(structured, errMsg)
+
+	temporary := strings.Contains(strings.ToLower(stderrOutput), "temporary failure")
+
+	return &SendError{Err: fmt.Errorf("%s: %w", errMsg, err), Temporary: temporary}
+}
+
+// SMTPNotifier sends email directly via net/smtp with STARTTLS and PLAIN auth
+type SMTPNotifier struct {
+	Host      string
+	Port      string
+	User      string
+	Pass      string
+	Recipient string
+	Logger    *slog.Logger
+}
+
+// Notify implements Notifier for SMTPNotifier
+func (n *SMTPNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	addr := n.Host + ":" + n.Port
+
+	logInfo(n.Logger, fmt.Sprintf("Attempting to send email to %s via SMTP (%s)", n.Recipient, addr))
+
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("smtp: failed to dial %s: %w", addr, err), Temporary: true}
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+			return &SendError{Err: fmt.Errorf("smtp: STARTTLS failed: %w", err), Temporary: true}
+		}
+	}
+
+	if n.User != "" {
+		auth := smtp.PlainAuth("", n.User, n.Pass, n.Host)
+		if err := client.Auth(auth); err != nil {
+			return &SendError{Err: fmt.Errorf("smtp: auth failed: %w", err)}
+		}
+	}
+
+	if err := client.Mail(n.User); err != nil {
+		return &SendError{Err: fmt.Errorf("smtp: MAIL FROM failed: %w", err), Temporary: isTemporarySMTPReply(err)}
+	}
+
+	if err := client.Rcpt(n.Recipient); err != nil {
+		return &SendError{Err: fmt.Errorf("smtp: RCPT TO failed: %w", err), Temporary: isTemporarySMTPReply(err)}
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("smtp: DATA failed: %w", err), Temporary: isTemporarySMTPReply(err)}
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", n.User, n.Recipient, subject, body)
+	if _, err := wc.Write([]byte(message)); err != nil {
+		return &SendError{Err: fmt.Errorf("smtp: failed writing message: %w", err), Temporary: true}
+	}
+
+	if err := wc.Close(); err != nil {
+		return &SendError{Err: fmt.Errorf("smtp: failed closing message: %w", err), Temporary: isTemporarySMTPReply(err)}
+	}
+
+	logInfo(n.Logger, fmt.Sprintf("Email sent successfully to %s via SMTP (%s)", n.Recipient, addr))
+
+	return client.Quit()
+}
+
+// isTemporarySMTPReply reports whether err is (or wraps) an SMTP reply in the
+// 4xx range, which by convention indicates a transient failure
+func isTemporarySMTPReply(err error) bool {
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+
+	return false
+}
+
+// WebhookNotifier POSTs a generic JSON payload compatible with
+// Slack/Discord/ntfy-style incoming webhooks
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+	Logger *slog.Logger
+}
+
+// webhookPayload is the JSON body POSTed to the configured webhook URL
+type webhookPayload struct {
+	Text    string `json:"text"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notify implements Notifier for WebhookNotifier
+func (n *WebhookNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	payload := webhookPayload{
+		Text:    fmt.Sprintf("%s\n%s", subject, body),
+		Subject: subject,
+		Body:    body,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("webhook: failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	logInfo(n.Logger, fmt.Sprintf("Attempting to POST webhook notification to %s", n.URL))
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("webhook: request failed: %w", err), Temporary: true}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &SendError{
+			Err:       fmt.Errorf("webhook: unexpected status %s", resp.Status),
+			Temporary: resp.StatusCode >= 500,
+		}
+	}
+
+	logInfo(n.Logger, fmt.Sprintf("Webhook notification sent successfully to %s", n.URL))
+
+	return nil
+}
+
+// buildNotifiers constructs the configured set of Notifiers from cfg,
+// returning a single Notifier that fans out to all of them. structured is
+// threaded into each notifier so their log lines honor '-log-format' too
+func buildNotifiers(cfg config, structured *slog.Logger) (Notifier, error) {
+
+	var notifiers []Notifier
+
+	for _, name := range cfg.Notifiers {
+		var n Notifier
+
+		switch strings.TrimSpace(name) {
+		case "mail":
+			if cfg.EmailRecipient == "" {
+				return nil, fmt.Errorf("%w: %q", errRecipientRequired, name)
+			}
+			n = &MailNotifier{Command: cfg.MailCommand, Recipient: cfg.EmailRecipient, Logger: structured}
+
+		case "sendmail":
+			if cfg.EmailRecipient == "" {
+				return nil, fmt.Errorf("%w: %q", errRecipientRequired, name)
+			}
+			n = &SendmailNotifier{Command: cfg.SendmailCommand, Recipient: cfg.EmailRecipient, From: cfg.Hostname, Logger: structured}
+
+		case "smtp":
+			if cfg.EmailRecipient == "" {
+				return nil, fmt.Errorf("%w: %q", errRecipientRequired, name)
+			}
+			n = &SMTPNotifier{
+				Host:      cfg.SMTPHost,
+				Port:      cfg.SMTPPort,
+				User:      cfg.SMTPUser,
+				Pass:      cfg.SMTPPass,
+				Recipient: cfg.EmailRecipient,
+				Logger:    structured,
+			}
+
+		case "webhook":
+			n = &WebhookNotifier{URL: cfg.WebhookURL, Logger: structured}
+
+		case "":
+			continue
+
+		default:
+			return nil, fmt.Errorf("%w: %q", errUnknownNotifier, name)
+		}
+
+		notifiers = append(notifiers, &RetryingNotifier{Notifier: n, Logger: structured})
+	}
+
+	if len(notifiers) == 0 {
+		return nil, errNoNotifiers
+	}
+
+	return &MultiNotifier{notifiers: notifiers}, nil
+}
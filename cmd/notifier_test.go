@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingNotifier returns the next error from errs on each call, recording
+// how many times Notify was invoked
+type countingNotifier struct {
+	errs  []error
+	calls int
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, subject, body string) error {
+
+	err := n.errs[n.calls]
+	n.calls++
+
+	return err
+}
+
+func withFastRetryBackoff(t *testing.T) {
+	t.Helper()
+
+	original := retryBackoff
+	retryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+
+	t.Cleanup(func() { retryBackoff = original })
+}
+
+func TestRetryingNotifierStopsAfterThreeAttempts(t *testing.T) {
+
+	withFastRetryBackoff(t)
+
+	temp := &SendError{Err: errors.New("temporary"), Temporary: true}
+	n := &countingNotifier{errs: []error{temp, temp, temp}}
+	r := &RetryingNotifier{Notifier: n}
+
+	err := r.Notify(context.Background(), "subject", "body")
+	if err == nil {
+		t.Fatal("expected an error after exhausting all retries")
+	}
+
+	if n.calls != len(retryBackoff) {
+		t.Fatalf("got %d attempts, want %d", n.calls, len(retryBackoff))
+	}
+}
+
+func TestRetryingNotifierSucceedsWithinBudget(t *testing.T) {
+
+	withFastRetryBackoff(t)
+
+	temp := &SendError{Err: errors.New("temporary"), Temporary: true}
+	n := &countingNotifier{errs: []error{temp, nil}}
+	r := &RetryingNotifier{Notifier: n}
+
+	if err := r.Notify(context.Background(), "subject", "body"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n.calls != 2 {
+		t.Fatalf("got %d attempts, want 2", n.calls)
+	}
+}
+
+func TestRetryingNotifierShortCircuitsOnPermanentError(t *testing.T) {
+
+	withFastRetryBackoff(t)
+
+	permanent := &SendError{Err: errors.New("permanent"), Temporary: false}
+	n := &countingNotifier{errs: []error{permanent, permanent, permanent}}
+	r := &RetryingNotifier{Notifier: n}
+
+	err := r.Notify(context.Background(), "subject", "body")
+	if !errors.Is(err, permanent) {
+		t.Fatalf("got error %v, want %v", err, permanent)
+	}
+
+	if n.calls != 1 {
+		t.Fatalf("got %d attempts, want 1 (no retries for a permanent error)", n.calls)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultVcgencmd = "vcgencmd"
+
+// SensorSource reads the current temperature from a hardware-exposed source
+type SensorSource interface {
+	ReadTemp(ctx context.Context) (float64, error)
+}
+
+// sensor pairs a named SensorSource with its own alert threshold
+type sensor struct {
+	Name      string
+	Source    SensorSource
+	Threshold float64
+}
+
+// ThermalZoneSource reads temperature (in millidegrees Celsius) from a
+// /sys/class/thermal/thermal_zoneN/temp file
+type ThermalZoneSource struct {
+	Path string
+}
+
+// ReadTemp implements SensorSource for ThermalZoneSource
+func (s *ThermalZoneSource) ReadTemp(ctx context.Context) (float64, error) {
+	return readMillidegreeFile(s.Path)
+}
+
+// HwmonSource reads temperature (in millidegrees Celsius) from a
+// /sys/class/hwmon/hwmonN/tempM_input file
+type HwmonSource struct {
+	Path string
+}
+
+// ReadTemp implements SensorSource for HwmonSource
+func (s *HwmonSource) ReadTemp(ctx context.Context) (float64, error) {
+	return readMillidegreeFile(s.Path)
+}
+
+// readMillidegreeFile reads a sysfs file containing a temperature in
+// millidegrees Celsius and returns it in degrees Celsius
+func readMillidegreeFile(path string) (float64, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read temperature file %s: %w", path, err)
+	}
+
+	tempStr := strings.TrimSpace(string(data))
+	tempVal, err := strconv.ParseFloat(tempStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse temperature value '%s' from %s: %w", tempStr, path, err)
+	}
+
+	return tempVal / 1000.0, nil
+}
+
+// VcgencmdSource reads the Raspberry Pi GPU temperature via the
+// 'vcgencmd measure_temp' command, which prints output like "temp=42.8'C"
+type VcgencmdSource struct {
+	Command string
+}
+
+// ReadTemp implements SensorSource for VcgencmdSource
+func (s *VcgencmdSource) ReadTemp(ctx context.Context) (float64, error) {
+
+	command := s.Command
+	if command == "" {
+		command = defaultVcgencmd
+	}
+
+	cmd := exec.CommandContext(ctx, command, "measure_temp")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("vcgencmd: failed to measure temperature: %v. Stderr: %s", err, stderr.String())
+	}
+
+	out := strings.TrimSpace(stdout.String())
+	out = strings.TrimPrefix(out, "temp=")
+	out = strings.TrimSuffix(out, "'C")
+
+	tempVal, err := strconv.ParseFloat(out, 64)
+	if err != nil {
+		return 0, fmt.Errorf("vcgencmd: failed to parse temperature value %q: %w", out, err)
+	}
+
+	return tempVal, nil
+}
+
+// sensorConfig describes one entry in the -sensors-config YAML file
+type sensorConfig struct {
+	Name      string  `yaml:"name"`
+	Source    string  `yaml:"source"`
+	Path      string  `yaml:"path"`
+	Command   string  `yaml:"command"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// sensorsFile is the top-level shape of the -sensors-config YAML file
+type sensorsFile struct {
+	Sensors []sensorConfig `yaml:"sensors"`
+}
+
+// buildSensors returns the sensors to monitor: those declared in
+// cfg.SensorConfigPath, or a single sensor reading the legacy
+// cpuTempFilePath/-threshold flags when no config file is given
+func buildSensors(cfg config) ([]sensor, error) {
+
+	if cfg.SensorConfigPath == "" {
+		return []sensor{{
+			Name:      "cpu",
+			Source:    &ThermalZoneSource{Path: cpuTempFilePath},
+			Threshold: cfg.TempThreshold,
+		}}, nil
+	}
+
+	data, err := os.ReadFile(cfg.SensorConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sensors config %s: %w", cfg.SensorConfigPath, err)
+	}
+
+	var parsed sensorsFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse sensors config %s: %w", cfg.SensorConfigPath, err)
+	}
+
+	if len(parsed.Sensors) == 0 {
+		return nil, fmt.Errorf("sensors config %s declares no sensors", cfg.SensorConfigPath)
+	}
+
+	sensors := make([]sensor, 0, len(parsed.Sensors))
+
+	for _, sc := range parsed.Sensors {
+		source, err := buildSensorSource(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		sensors = append(sensors, sensor{Name: sc.Name, Source: source, Threshold: sc.Threshold})
+	}
+
+	return sensors, nil
+}
+
+// buildSensorSource constructs the SensorSource described by sc
+func buildSensorSource(sc sensorConfig) (SensorSource, error) {
+
+	switch sc.Source {
+	case "", "thermal_zone":
+		return &ThermalZoneSource{Path: sc.Path}, nil
+
+	case "hwmon":
+		return &HwmonSource{Path: sc.Path}, nil
+
+	case "vcgencmd":
+		return &VcgencmdSource{Command: sc.Command}, nil
+
+	default:
+		return nil, fmt.Errorf("sensor %q: unknown source %q", sc.Name, sc.Source)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// maxSamples is the number of recent temperature readings retained for
+// inclusion in notification templates
+const maxSamples = 10
+
+// Default subject/body templates, used when -subject-template/-body-template
+// are not set
+var (
+	defaultSubjectTemplate = fmt.Sprintf(`%s: {{.Sensor}} Temp {{.EventType}} ({{.Hostname}}): {{printf "%%.2f" .CurrentTemp}}°C`, appName)
+
+	defaultBodyTemplate = fmt.Sprintf(`%s on {{.Hostname}}
+Sensor: {{.Sensor}}
+Event: {{.EventType}}
+Threshold temp: {{printf "%%.2f" .Threshold}}°C
+Current temp: {{printf "%%.2f" .CurrentTemp}}°C
+Uptime: {{.Uptime}}
+Timestamp: {{.Timestamp}}
+Recent samples (°C): {{range $i, $t := .Samples}}{{if $i}}, {{end}}{{printf "%%.2f" $t}}{{end}}
+`, appName)
+)
+
+// templateContext is the data made available to subject/body templates
+type templateContext struct {
+	Hostname    string
+	Sensor      string
+	CurrentTemp float64
+	Threshold   float64
+	Timestamp   string
+	Uptime      time.Duration
+	EventType   string
+	Samples     []float64
+}
+
+// notificationTemplates holds the parsed subject/body templates used to
+// render notifications
+type notificationTemplates struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// loadTemplates parses the subject/body templates, reading them from
+// cfg.SubjectTemplatePath/cfg.BodyTemplatePath when set, or falling back to
+// the built-in defaults. It fails fast so a bad template is caught at
+// startup rather than the first time a notification is sent
+func loadTemplates(cfg config) (notificationTemplates, error) {
+
+	subjectSrc := defaultSubjectTemplate
+	if cfg.SubjectTemplatePath != "" {
+		data, err := os.ReadFile(cfg.SubjectTemplatePath)
+		if err != nil {
+			return notificationTemplates{}, fmt.Errorf("failed to read subject template %s: %w", cfg.SubjectTemplatePath, err)
+		}
+		subjectSrc = string(data)
+	}
+
+	subjectTmpl, err := template.New("subject").Parse(subjectSrc)
+	if err != nil {
+		return notificationTemplates{}, fmt.Errorf("failed to parse subject template: %w", err)
+	}
+
+	bodySrc := defaultBodyTemplate
+	if cfg.BodyTemplatePath != "" {
+		data, err := os.ReadFile(cfg.BodyTemplatePath)
+		if err != nil {
+			return notificationTemplates{}, fmt.Errorf("failed to read body template %s: %w", cfg.BodyTemplatePath, err)
+		}
+		bodySrc = string(data)
+	}
+
+	bodyTmpl, err := template.New("body").Parse(bodySrc)
+	if err != nil {
+		return notificationTemplates{}, fmt.Errorf("failed to parse body template: %w", err)
+	}
+
+	return notificationTemplates{subject: subjectTmpl, body: bodyTmpl}, nil
+}
+
+// render executes the subject/body templates against ctx
+func (t notificationTemplates) render(ctx templateContext) (subject, body string, err error) {
+
+	var subjectBuf, bodyBuf strings.Builder
+
+	if err := t.subject.Execute(&subjectBuf, ctx); err != nil {
+		return "", "", fmt.Errorf("failed to render subject template: %w", err)
+	}
+
+	if err := t.body.Execute(&bodyBuf, ctx); err != nil {
+		return "", "", fmt.Errorf("failed to render body template: %w", err)
+	}
+
+	return subjectBuf.String(), bodyBuf.String(), nil
+}